@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIError is the structured error body returned by the JSON API.
+type APIError struct {
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+func writeJSONError(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{Status: status, Description: description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleAPITasks serves GET /api/tasks (list) and POST /api/tasks (create).
+func (a *AppState) handleAPITasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+		var tasks []Task
+		err := a.withDBSlot(ctx, 1, func() error {
+			var err error
+			tasks, err = a.getTasks(ctx)
+			return err
+		})
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, tasks)
+	case http.MethodPost:
+		var body struct {
+			Name string `json:"name"`
+			XP   int    `json:"xp"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if err := a.AddTask(ctx, body.Name, body.XP); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"message": "task created"})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleAPITaskAction dispatches /api/tasks/{id}/complete.
+func (a *AppState) handleAPITaskAction(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[1] != "complete" {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid task id")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	xp, err := a.CompleteTask(r.Context(), id)
+	if errors.Is(err, ErrTaskNotFound) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	totalXP, _ := a.Snapshot()
+	writeJSON(w, http.StatusOK, map[string]int{"xp": xp, "total_xp": totalXP})
+}
+
+// handleAPIUnlockables serves GET /api/unlockables (list) and POST /api/unlockables (create).
+func (a *AppState) handleAPIUnlockables(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+		var unlockables []Unlockable
+		err := a.withDBSlot(ctx, 1, func() error {
+			var err error
+			unlockables, err = a.getUnlockables(ctx)
+			return err
+		})
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, unlockables)
+	case http.MethodPost:
+		var body struct {
+			Level       int    `json:"level"`
+			Description string `json:"description"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if err := a.AddUnlockable(ctx, body.Level, body.Description); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"message": "unlockable created"})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleAPIState serves GET /api/state, a snapshot of the player's overall progress.
+func (a *AppState) handleAPIState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	totalXP, unlocked := a.Snapshot()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_xp":      totalXP,
+		"current_level": totalXP / 1000,
+		"progress":      a.GetProgressPercentage(),
+		"unlocked":      unlocked,
+	})
+}
+
+// registerAPIRoutes wires up the JSON API alongside the existing HTML endpoints.
+func registerAPIRoutes(state *AppState) {
+	http.HandleFunc("/api/tasks", state.handleAPITasks)
+	http.HandleFunc("/api/tasks/bulk", state.handleAPIBulkTasks)
+	http.HandleFunc("/api/tasks/", state.handleAPITaskAction)
+	http.HandleFunc("/api/unlockables", state.handleAPIUnlockables)
+	http.HandleFunc("/api/state", state.handleAPIState)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// RowError reports a single row that failed parsing or validation during a
+// bulk import. Row is the row's position in the original request body (CSV
+// record index or NDJSON line number, both 0-based), not its position in any
+// filtered-down slice, so callers can find the bad row in the file they sent.
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// bulkRow pairs a parsed task with the row it came from in the request body,
+// so validation errors can still be attributed to the original row after
+// earlier rows have been skipped or filtered out. Insert errors are reported
+// by task name and abort the whole batch.
+type bulkRow struct {
+	Row  int
+	Task Task
+}
+
+// BulkAddTasks inserts many tasks in one shot via PostgreSQL COPY. Invalid rows
+// are skipped and reported in rowErrs; when atomic is true, the first invalid
+// row aborts the whole batch instead.
+func (a *AppState) BulkAddTasks(ctx context.Context, rows []bulkRow, atomic bool) (inserted int, rowErrs []RowError, err error) {
+	valid := make([]Task, 0, len(rows))
+	for _, r := range rows {
+		if r.Task.Name == "" || r.Task.XP <= 0 {
+			msg := "invalid task: name is required and xp must be positive"
+			if atomic {
+				return 0, nil, fmt.Errorf("row %d: %s", r.Row, msg)
+			}
+			rowErrs = append(rowErrs, RowError{Row: r.Row, Error: msg})
+			continue
+		}
+		valid = append(valid, r.Task)
+	}
+
+	if len(valid) == 0 {
+		return 0, rowErrs, nil
+	}
+
+	err = a.withDBSlot(ctx, a.maxDBConns, func() error {
+		tx, err := a.db.BeginTx(ctx, nil)
+		if err != nil {
+			return dbErr(ctx, "error starting transaction", err)
+		}
+		defer tx.Rollback()
+
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("tasks", "name", "xp", "completed"))
+		if err != nil {
+			return dbErr(ctx, "error preparing copy-in statement", err)
+		}
+
+		for _, t := range valid {
+			if _, err := stmt.ExecContext(ctx, t.Name, t.XP, t.Completed); err != nil {
+				return dbErr(ctx, fmt.Sprintf("error copying task %q", t.Name), err)
+			}
+		}
+
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return dbErr(ctx, "error flushing copy-in", err)
+		}
+
+		if err := stmt.Close(); err != nil {
+			return dbErr(ctx, "error closing copy-in statement", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return dbErr(ctx, "error committing bulk import", err)
+		}
+
+		inserted = len(valid)
+		return nil
+	})
+	if err != nil {
+		return 0, rowErrs, err
+	}
+
+	return inserted, rowErrs, nil
+}
+
+// handleAPIBulkTasks serves POST /api/tasks/bulk, importing a CSV or
+// newline-delimited JSON body of tasks. Pass ?atomic=1 to abort the whole
+// import on the first invalid row instead of skipping it.
+func (a *AppState) handleAPIBulkTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	atomic := r.URL.Query().Get("atomic") == "1"
+
+	rows, parseErrs, err := parseBulkTasksBody(r, atomic)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	inserted, rowErrs, err := a.BulkAddTasks(r.Context(), rows, atomic)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := append(parseErrs, rowErrs...)
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Row < errs[j].Row })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"inserted": inserted,
+		"errors":   errs,
+	})
+}
+
+// parseBulkTasksBody parses the bulk import body as CSV or newline-delimited
+// JSON, based on the request's Content-Type. A malformed row is skipped and
+// reported in the returned RowErrors, unless atomic is true, in which case
+// the first malformed row aborts parsing entirely.
+func parseBulkTasksBody(r *http.Request, atomic bool) ([]bulkRow, []RowError, error) {
+	if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+		return parseBulkTasksCSV(r.Body, atomic)
+	}
+	return parseBulkTasksNDJSON(r.Body, atomic)
+}
+
+// parseBulkTasksCSV parses rows of "name,xp", tolerating an optional header row.
+func parseBulkTasksCSV(body io.Reader, atomic bool) ([]bulkRow, []RowError, error) {
+	reader := csv.NewReader(body)
+	// Allow a varying column count so short rows are reported per row below
+	// instead of failing the whole body.
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing CSV body: %w", err)
+	}
+
+	var rows []bulkRow
+	var rowErrs []RowError
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue
+		}
+		if len(record) < 2 {
+			msg := "expected at least 2 columns (name, xp)"
+			if atomic {
+				return nil, nil, fmt.Errorf("row %d: %s", i, msg)
+			}
+			rowErrs = append(rowErrs, RowError{Row: i, Error: msg})
+			continue
+		}
+		xp, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			msg := fmt.Sprintf("invalid xp %q", record[1])
+			if atomic {
+				return nil, nil, fmt.Errorf("row %d: %s", i, msg)
+			}
+			rowErrs = append(rowErrs, RowError{Row: i, Error: msg})
+			continue
+		}
+		rows = append(rows, bulkRow{Row: i, Task: Task{Name: strings.TrimSpace(record[0]), XP: xp}})
+	}
+	return rows, rowErrs, nil
+}
+
+// parseBulkTasksNDJSON parses one JSON-encoded task per line.
+func parseBulkTasksNDJSON(body io.Reader, atomic bool) ([]bulkRow, []RowError, error) {
+	var rows []bulkRow
+	var rowErrs []RowError
+	scanner := bufio.NewScanner(body)
+	for line := 0; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var t Task
+		if err := json.Unmarshal([]byte(text), &t); err != nil {
+			msg := fmt.Sprintf("error parsing JSON: %v", err)
+			if atomic {
+				return nil, nil, fmt.Errorf("row %d: %s", line, msg)
+			}
+			rowErrs = append(rowErrs, RowError{Row: line, Error: msg})
+			continue
+		}
+		rows = append(rows, bulkRow{Row: line, Task: t})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading request body: %w", err)
+	}
+	return rows, rowErrs, nil
+}
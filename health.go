@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+)
+
+// SetDraining marks the app as shutting down so readyz starts failing and
+// load balancers stop sending it new traffic.
+func (a *AppState) SetDraining(draining bool) {
+	a.draining.Store(draining)
+}
+
+// handleHealthz reports that the process is alive. It never fails: a 500
+// here would mean the Go runtime itself has stopped serving requests.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the app can currently serve traffic: the DB
+// must be reachable and the app must not be draining for shutdown.
+func (a *AppState) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if a.draining.Load() {
+		writeJSONError(w, http.StatusServiceUnavailable, "draining")
+		return
+	}
+
+	if err := a.db.PingContext(r.Context()); err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "database unreachable: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// registerHealthRoutes wires up the health/readiness endpoints.
+func registerHealthRoutes(state *AppState) {
+	http.HandleFunc("/healthz", handleHealthz)
+	http.HandleFunc("/readyz", state.handleReadyz)
+}
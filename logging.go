@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ctxKey string
+
+const (
+	loggerCtxKey    ctxKey = "logger"
+	requestIDCtxKey ctxKey = "request_id"
+	dbTimeCtxKey    ctxKey = "db_time"
+)
+
+// withDBTimeTracking installs a DB-time accumulator in ctx. AppState.withDBSlot
+// adds to it via addDBTime, and withRequestLogging reads the total back out
+// once the handler returns so the access log carries time spent in the DB
+// alongside the overall request duration.
+func withDBTimeTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dbTimeCtxKey, new(atomic.Int64))
+}
+
+// addDBTime records d against the request's DB-time accumulator, if the
+// context has one (it won't outside a request, e.g. in the scheduler).
+func addDBTime(ctx context.Context, d time.Duration) {
+	if acc, ok := ctx.Value(dbTimeCtxKey).(*atomic.Int64); ok {
+		acc.Add(int64(d))
+	}
+}
+
+// dbTimeFromContext returns the accumulated DB time recorded against ctx, or
+// zero if the context isn't tracking it.
+func dbTimeFromContext(ctx context.Context) time.Duration {
+	if acc, ok := ctx.Value(dbTimeCtxKey).(*atomic.Int64); ok {
+		return time.Duration(acc.Load())
+	}
+	return 0
+}
+
+// newLogger builds the application logger. APP_MODE=production emits JSON
+// (for log aggregation); anything else emits human-readable text.
+func newLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+
+	var handler slog.Handler
+	if os.Getenv("APP_MODE") == "production" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	return slog.New(handler)
+}
+
+// loggerFromContext returns the request-scoped logger stashed by
+// withRequestLogging, carrying that request's correlation ID. Outside a
+// request it falls back to slog.Default().
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerCtxKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// statusWriter captures the response status code for request logging.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// withRequestLogging assigns each request a UUID, threads a *slog.Logger
+// carrying it through the request context, and logs method/path/status/
+// duration once the handler returns. AppState methods pull the logger back
+// out via loggerFromContext so DB errors carry the same correlation ID.
+func withRequestLogging(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := uuid.NewString()
+		reqLogger := logger.With("request_id", requestID)
+
+		ctx := context.WithValue(r.Context(), loggerCtxKey, reqLogger)
+		ctx = context.WithValue(ctx, requestIDCtxKey, requestID)
+		ctx = withDBTimeTracking(ctx)
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		reqLogger.Info("request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"db_time_ms", dbTimeFromContext(ctx).Milliseconds(),
+		)
+	})
+}
+
+// Trace annotates err with the caller's file and line, analogous to
+// juju/errors.Trace, so a 500 in production is diagnosable from logs alone.
+func Trace(err error) error {
+	if err == nil {
+		return nil
+	}
+	_, file, line, ok := runtime.Caller(1)
+	if !ok {
+		return err
+	}
+	return fmt.Errorf("%s:%d: %w", file, line, err)
+}
+
+// dbErr logs a DB error against the request-scoped logger (so it carries the
+// request's correlation ID) and returns it annotated with msg and with the
+// file and line of dbErr's caller, instead of letting it be swallowed
+// silently into http.Error.
+func dbErr(ctx context.Context, msg string, err error) error {
+	loggerFromContext(ctx).Error(msg, "err", err)
+	wrapped := fmt.Errorf("%s: %w", msg, err)
+	if _, file, line, ok := runtime.Caller(1); ok {
+		return fmt.Errorf("%s:%d: %w", file, line, wrapped)
+	}
+	return wrapped
+}
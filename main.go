@@ -1,20 +1,31 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"golang.org/x/sync/semaphore"
 )
 
+// ErrTaskNotFound is returned when completing a task that doesn't exist or is already completed.
+var ErrTaskNotFound = errors.New("task already completed or not found")
+
 //go:embed templates/*
 var templatesFS embed.FS
 
@@ -31,6 +42,17 @@ type Unlockable struct {
 	Description string
 }
 
+// RecurringTask defines a task that is re-materialized on a cron schedule,
+// e.g. "daily standup, 50 XP" every morning.
+type RecurringTask struct {
+	ID        int
+	Name      string
+	XP        int
+	CronExpr  string
+	LastRunAt *time.Time
+	CreatedAt time.Time
+}
+
 // Configuration struct
 type Config struct {
 	DatabaseURL string
@@ -69,16 +91,77 @@ const (
 		unlocked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS recurring_tasks (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		xp INTEGER NOT NULL CHECK (xp > 0),
+		cron_expr VARCHAR(100) NOT NULL,
+		last_run_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_tasks_completed ON tasks(completed);
 	CREATE INDEX IF NOT EXISTS idx_unlockables_level ON unlockables(level);`
 )
 
 type AppState struct {
-	sync.RWMutex // Using RWMutex for better concurrent access
-	db           *sql.DB
-	TotalXP      int
-	Unlocked     map[int]bool
-	stmts        map[string]*sql.Stmt
+	db         *sql.DB
+	stmts      map[string]*sql.Stmt
+	sem        *semaphore.Weighted // bounds concurrent DB access
+	maxDBConns int64
+	draining   atomic.Bool // set while the server is shutting down
+
+	// mu guards TotalXP and Unlocked. The DB semaphore serializes writers
+	// against each other but never runs for plain in-memory reads (template
+	// rendering, the JSON API), so it can't stand in for a mutex here.
+	mu       sync.RWMutex
+	TotalXP  int
+	Unlocked map[int]bool
+}
+
+// Snapshot returns a consistent, race-free copy of the player's progress:
+// the total XP and the set of unlocked levels. Callers that only read this
+// state (template rendering, the JSON API) must go through Snapshot rather
+// than touching TotalXP/Unlocked directly, since those fields are mutated
+// concurrently from CompleteTask.
+func (a *AppState) Snapshot() (totalXP int, unlocked map[int]bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	totalXP = a.TotalXP
+	unlocked = make(map[int]bool, len(a.Unlocked))
+	for level, ok := range a.Unlocked {
+		unlocked[level] = ok
+	}
+	return totalXP, unlocked
+}
+
+// Close closes every prepared statement held by the app. It does not close
+// the underlying *sql.DB; callers own that separately.
+func (a *AppState) Close() error {
+	var firstErr error
+	for name, stmt := range a.stmts {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("error closing prepared statement %s: %w", name, err)
+		}
+	}
+	return firstErr
+}
+
+// withDBSlot runs fn while holding weight units of the DB semaphore. Read
+// handlers acquire a single unit so they can run concurrently; writers
+// acquire the full weight so mutations are serialized against every other
+// DB-bound handler. Acquisition is tied to ctx so a canceled/slow client
+// doesn't pile up behind an exhausted pool.
+func (a *AppState) withDBSlot(ctx context.Context, weight int64, fn func() error) error {
+	if err := a.sem.Acquire(ctx, weight); err != nil {
+		return fmt.Errorf("error acquiring db slot: %w", err)
+	}
+	defer a.sem.Release(weight)
+
+	start := time.Now()
+	defer func() { addDBTime(ctx, time.Since(start)) }()
+	return fn()
 }
 
 // Initialize database connection
@@ -102,11 +185,13 @@ func initDB(config Config) (*sql.DB, error) {
 }
 
 // Replace loadState with initAppState
-func initAppState(db *sql.DB) (*AppState, error) {
+func initAppState(db *sql.DB, maxDBConns int) (*AppState, error) {
 	state := &AppState{
-		db:       db,
-		Unlocked: make(map[int]bool),
-		stmts:    make(map[string]*sql.Stmt),
+		db:         db,
+		Unlocked:   make(map[int]bool),
+		stmts:      make(map[string]*sql.Stmt),
+		sem:        semaphore.NewWeighted(int64(maxDBConns)),
+		maxDBConns: int64(maxDBConns),
 	}
 
 	// Prepare statements
@@ -163,10 +248,10 @@ func (a *AppState) loadUnlockedLevels() error {
 }
 
 // Use prepared statements and caching for frequently accessed methods
-func (a *AppState) getTasks() ([]Task, error) {
-	rows, err := a.stmts["getTasks"].Query()
+func (a *AppState) getTasks(ctx context.Context) ([]Task, error) {
+	rows, err := a.stmts["getTasks"].QueryContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error getting tasks: %w", err)
+		return nil, dbErr(ctx, "error getting tasks", err)
 	}
 	defer rows.Close()
 
@@ -174,17 +259,17 @@ func (a *AppState) getTasks() ([]Task, error) {
 	for rows.Next() {
 		var t Task
 		if err := rows.Scan(&t.ID, &t.Name, &t.XP, &t.Completed); err != nil {
-			return nil, fmt.Errorf("error scanning task: %w", err)
+			return nil, dbErr(ctx, "error scanning task", err)
 		}
 		tasks = append(tasks, t)
 	}
 	return tasks, nil
 }
 
-func (a *AppState) getUnlockables() ([]Unlockable, error) {
-	rows, err := a.db.Query("SELECT id, level, description FROM unlockables")
+func (a *AppState) getUnlockables(ctx context.Context) ([]Unlockable, error) {
+	rows, err := a.db.QueryContext(ctx, "SELECT id, level, description FROM unlockables")
 	if err != nil {
-		return nil, err
+		return nil, dbErr(ctx, "error getting unlockables", err)
 	}
 	defer rows.Close()
 
@@ -192,31 +277,122 @@ func (a *AppState) getUnlockables() ([]Unlockable, error) {
 	for rows.Next() {
 		var u Unlockable
 		if err := rows.Scan(&u.ID, &u.Level, &u.Description); err != nil {
-			return nil, err
+			return nil, dbErr(ctx, "error scanning unlockable", err)
 		}
 		unlockables = append(unlockables, u)
 	}
 	return unlockables, nil
 }
 
+// AddTask validates and inserts a new task, shared by the HTML and JSON API layers.
+// It acquires the full DB semaphore weight so it serializes against every
+// other write and against in-progress reads.
+func (a *AppState) AddTask(ctx context.Context, name string, xp int) error {
+	if name == "" || xp <= 0 {
+		return fmt.Errorf("invalid input: name is required and xp must be positive")
+	}
+
+	return a.withDBSlot(ctx, a.maxDBConns, func() error {
+		if _, err := a.stmts["addTask"].ExecContext(ctx, name, xp); err != nil {
+			return dbErr(ctx, "error adding task", err)
+		}
+		return nil
+	})
+}
+
+// CompleteTask marks a task as completed, awards its XP, and unlocks any levels
+// reached as a result. It returns the XP awarded.
+func (a *AppState) CompleteTask(ctx context.Context, taskID int) (int, error) {
+	var xp int
+	err := a.withDBSlot(ctx, a.maxDBConns, func() error {
+		tx, err := a.db.BeginTx(ctx, nil)
+		if err != nil {
+			return dbErr(ctx, "error starting transaction", err)
+		}
+		defer tx.Rollback()
+
+		err = tx.QueryRowContext(ctx, "UPDATE tasks SET completed = true WHERE id = $1 AND completed = false RETURNING xp", taskID).Scan(&xp)
+		if err == sql.ErrNoRows {
+			return ErrTaskNotFound
+		}
+		if err != nil {
+			return dbErr(ctx, "error completing task", err)
+		}
+
+		a.mu.Lock()
+		previousLevel := a.TotalXP / 1000
+		a.TotalXP += xp
+		newLevel := a.TotalXP / 1000
+		totalXP := a.TotalXP
+		if newLevel > previousLevel {
+			for level := previousLevel + 1; level <= newLevel; level++ {
+				a.Unlocked[level] = true
+			}
+		}
+		a.mu.Unlock()
+
+		if _, err := tx.ExecContext(ctx, "UPDATE app_state SET total_xp = $1 WHERE id = 1", totalXP); err != nil {
+			return dbErr(ctx, "error updating total xp", err)
+		}
+
+		if newLevel > previousLevel {
+			for level := previousLevel + 1; level <= newLevel; level++ {
+				if _, err := tx.ExecContext(ctx, "INSERT INTO unlocked_levels (level, unlocked) VALUES ($1, true)", level); err != nil {
+					return dbErr(ctx, "error unlocking level", err)
+				}
+			}
+		}
+
+		return tx.Commit()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return xp, nil
+}
+
+// AddUnlockable validates and inserts a new unlockable.
+func (a *AppState) AddUnlockable(ctx context.Context, level int, description string) error {
+	if description == "" {
+		return fmt.Errorf("description is required")
+	}
+	if level < 0 {
+		return fmt.Errorf("level must be positive")
+	}
+
+	return a.withDBSlot(ctx, a.maxDBConns, func() error {
+		if _, err := a.stmts["addUnlockable"].ExecContext(ctx, level, description); err != nil {
+			return dbErr(ctx, "error adding unlockable", err)
+		}
+		return nil
+	})
+}
+
 func main() {
 
 	// load env
 	godotenv.Load()
-	
-	db, err := initDB(Config{
+
+	logger := newLogger()
+	slog.SetDefault(logger)
+
+	config := Config{
 		DatabaseURL: os.Getenv("DATABASE_URL"),
 		ServerPort:  ":8080",
 		MaxDBConns:  10,
-	})
+	}
+
+	db, err := initDB(config)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("error initializing database", "err", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
-	state, err := initAppState(db)
+	state, err := initAppState(db, config.MaxDBConns)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("error initializing app state", "err", err)
+		os.Exit(1)
 	}
 
 	funcMap := template.FuncMap{
@@ -234,28 +410,31 @@ func main() {
 	tmpl := template.Must(template.New("").Funcs(funcMap).ParseFS(templatesFS, "templates/*.html"))
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		state.Lock()
-		defer state.Unlock()
-		
-		tasks, err := state.getTasks()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		
-		unlockables, err := state.getUnlockables()
+		ctx := r.Context()
+
+		var tasks []Task
+		var unlockables []Unlockable
+		err := state.withDBSlot(ctx, 1, func() error {
+			var err error
+			if tasks, err = state.getTasks(ctx); err != nil {
+				return err
+			}
+			unlockables, err = state.getUnlockables(ctx)
+			return err
+		})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		currentLevel := state.TotalXP / 1000
+		totalXP, unlocked := state.Snapshot()
+		currentLevel := totalXP / 1000
 		err = tmpl.ExecuteTemplate(w, "index.html", map[string]interface{}{
-			"TotalXP":     state.TotalXP,
+			"TotalXP":     totalXP,
 			"CurrentLevel": currentLevel,
 			"Tasks":       tasks,
 			"Unlockables": unlockables,
-			"Unlocked":    state.Unlocked,
+			"Unlocked":    unlocked,
 			"Progress":    state.GetProgressPercentage(),
 		})
 		if err != nil {
@@ -269,44 +448,40 @@ func main() {
 			return
 		}
 
-		state.Lock()
-		defer state.Unlock()
+		ctx := r.Context()
 
 		name := r.FormValue("name")
 		var xp int
 		fmt.Sscanf(r.FormValue("xp"), "%d", &xp)
 
-		if name == "" || xp <= 0 {
-			http.Error(w, "Invalid input", http.StatusBadRequest)
+		if err := state.AddTask(ctx, name, xp); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Use prepared statement
-		_, err := state.stmts["addTask"].Exec(name, xp)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		
-		tasks, err := state.getTasks()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		
-		unlockables, err := state.getUnlockables()
+		var tasks []Task
+		var unlockables []Unlockable
+		err := state.withDBSlot(ctx, 1, func() error {
+			var err error
+			if tasks, err = state.getTasks(ctx); err != nil {
+				return err
+			}
+			unlockables, err = state.getUnlockables(ctx)
+			return err
+		})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		currentLevel := state.TotalXP / 1000
+		totalXP, unlocked := state.Snapshot()
+		currentLevel := totalXP / 1000
 		err = tmpl.ExecuteTemplate(w, "app.html", map[string]interface{}{
-			"TotalXP":     state.TotalXP,
+			"TotalXP":     totalXP,
 			"CurrentLevel": currentLevel,
 			"Tasks":       tasks,
 			"Unlockables": unlockables,
-			"Unlocked":    state.Unlocked,
+			"Unlocked":    unlocked,
 			"Progress":    state.GetProgressPercentage(),
 		})
 		if err != nil {
@@ -315,24 +490,14 @@ func main() {
 	})
 
 	http.HandleFunc("/add-xp", func(w http.ResponseWriter, r *http.Request) {
-		state.Lock()
-		defer state.Unlock()
+		ctx := r.Context()
 
-		taskID := r.FormValue("task")
-		
-		// Start transaction
-		tx, err := state.db.Begin()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		defer tx.Rollback()
+		var taskID int
+		fmt.Sscanf(r.FormValue("task"), "%d", &taskID)
 
-		// Get task XP and mark as completed
-		var xp int
-		err = tx.QueryRow("UPDATE tasks SET completed = true WHERE id = $1 AND completed = false RETURNING xp", taskID).Scan(&xp)
-		if err == sql.ErrNoRows {
-			http.Error(w, "Task already completed or not found", http.StatusBadRequest)
+		_, err := state.CompleteTask(ctx, taskID)
+		if errors.Is(err, ErrTaskNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		if err != nil {
@@ -340,51 +505,28 @@ func main() {
 			return
 		}
 
-		previousLevel := state.TotalXP / 1000
-		state.TotalXP += xp
-		newLevel := state.TotalXP / 1000
-
-		// Update total XP
-		_, err = tx.Exec("UPDATE app_state SET total_xp = $1 WHERE id = 1", state.TotalXP)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Handle level-ups
-		if newLevel > previousLevel {
-			for level := previousLevel + 1; level <= newLevel; level++ {
-				_, err = tx.Exec("INSERT INTO unlocked_levels (level, unlocked) VALUES ($1, true)", level)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				state.Unlocked[level] = true
+		var tasks []Task
+		var unlockables []Unlockable
+		err = state.withDBSlot(ctx, 1, func() error {
+			var err error
+			if tasks, err = state.getTasks(ctx); err != nil {
+				return err
 			}
-		}
-
-		if err := tx.Commit(); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		currentLevel := state.TotalXP / 1000
-		tasks, err := state.getTasks()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		unlockables, err := state.getUnlockables()
+			unlockables, err = state.getUnlockables(ctx)
+			return err
+		})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		totalXP, unlocked := state.Snapshot()
+		currentLevel := totalXP / 1000
 		err = tmpl.ExecuteTemplate(w, "app.html", map[string]interface{}{
-			"TotalXP":     state.TotalXP,
+			"TotalXP":     totalXP,
 			"CurrentLevel": currentLevel,
 			"Tasks":       tasks,
 			"Unlockables": unlockables,
-			"Unlocked":    state.Unlocked,
+			"Unlocked":    unlocked,
 			"Progress":    state.GetProgressPercentage(),
 		})
 		if err != nil {
@@ -399,49 +541,40 @@ func main() {
 			return
 		}
 
-		state.Lock()
-		defer state.Unlock()
+		ctx := r.Context()
 
 		var level int
 		fmt.Sscanf(r.FormValue("level"), "%d", &level)
 		description := r.FormValue("description")
 
-		if description == "" {
-			http.Error(w, "Description is required", http.StatusBadRequest)
-			return
-		}
-
-		if level < 0 {
-			http.Error(w, "Level must be positive", http.StatusBadRequest)
-			return
-		}
-
-		_, err := state.db.Exec("INSERT INTO unlockables (level, description) VALUES ($1, $2)", level, description)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := state.AddUnlockable(ctx, level, description); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Replace the comment with actual rendering
-		tasks, err := state.getTasks()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		
-		unlockables, err := state.getUnlockables()
+		var tasks []Task
+		var unlockables []Unlockable
+		err := state.withDBSlot(ctx, 1, func() error {
+			var err error
+			if tasks, err = state.getTasks(ctx); err != nil {
+				return err
+			}
+			unlockables, err = state.getUnlockables(ctx)
+			return err
+		})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		currentLevel := state.TotalXP / 1000
+		totalXP, unlocked := state.Snapshot()
+		currentLevel := totalXP / 1000
 		err = tmpl.ExecuteTemplate(w, "index.html", map[string]interface{}{
-			"TotalXP":     state.TotalXP,
+			"TotalXP":     totalXP,
 			"CurrentLevel": currentLevel,
 			"Tasks":       tasks,
 			"Unlockables": unlockables,
-			"Unlocked":    state.Unlocked,
+			"Unlocked":    unlocked,
 			"Progress":    state.GetProgressPercentage(),
 		})
 		if err != nil {
@@ -449,10 +582,75 @@ func main() {
 		}
 	})
 
-	http.ListenAndServe(":8080", nil)
+	registerAPIRoutes(state)
+	registerHealthRoutes(state)
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: os.Getenv("REDIS_ADDR"),
+	})
+	recurringQueue := NewRecurringTaskQueue(redisClient)
+	registerRecurringTaskRoutes(state)
+
+	schedulerCtx, cancelScheduler := context.WithCancel(context.Background())
+	defer cancelScheduler()
+	StartScheduler(schedulerCtx, state, recurringQueue)
+	go func() {
+		if err := StartWorkerPool(schedulerCtx, recurringQueue); err != nil {
+			logger.Error("worker pool stopped", "err", err)
+		}
+	}()
+
+	srv := &http.Server{
+		Addr:    config.ServerPort,
+		Handler: withRequestLogging(logger, http.DefaultServeMux),
+	}
+
+	serverErrCh := make(chan error, 1)
+	go func() {
+		logger.Info("exxp listening", "addr", config.ServerPort)
+		serverErrCh <- srv.ListenAndServe()
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErrCh:
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("server stopped unexpectedly", "err", err)
+		}
+	case sig := <-sigCh:
+		logger.Info("shutdown signal received, draining", "signal", sig.String())
+		state.SetDraining(true)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod())
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("error during graceful shutdown", "err", err)
+		}
+	}
+
+	cancelScheduler()
+	if err := state.Close(); err != nil {
+		logger.Error("error closing prepared statements", "err", err)
+	}
+}
+
+// shutdownGracePeriod controls how long Shutdown waits for in-flight
+// requests to finish, configurable via SHUTDOWN_GRACE_PERIOD (seconds).
+func shutdownGracePeriod() time.Duration {
+	if v := os.Getenv("SHUTDOWN_GRACE_PERIOD"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 15 * time.Second
 }
 
 func (a *AppState) GetProgressPercentage() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	currentLevelXP := a.TotalXP % 1000
 	return int(float64(currentLevelXP) / 1000 * 100)
 }
\ No newline at end of file
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// getRecurringTasks lists all recurring task definitions. Unlike getTasks and
+// getUnlockables, whose callers hold the DB slot, it acquires a single DB
+// semaphore unit itself so it can run concurrently with other readers.
+func (a *AppState) getRecurringTasks(ctx context.Context) ([]RecurringTask, error) {
+	var recurring []RecurringTask
+	err := a.withDBSlot(ctx, 1, func() error {
+		rows, err := a.db.QueryContext(ctx, "SELECT id, name, xp, cron_expr, last_run_at, created_at FROM recurring_tasks")
+		if err != nil {
+			return dbErr(ctx, "error getting recurring tasks", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rt RecurringTask
+			if err := rows.Scan(&rt.ID, &rt.Name, &rt.XP, &rt.CronExpr, &rt.LastRunAt, &rt.CreatedAt); err != nil {
+				return dbErr(ctx, "error scanning recurring task", err)
+			}
+			recurring = append(recurring, rt)
+		}
+		return nil
+	})
+	return recurring, err
+}
+
+// AddRecurringTask validates and inserts a new recurring task definition. It
+// acquires the full DB semaphore weight so it serializes against every other
+// write and against in-progress reads.
+func (a *AppState) AddRecurringTask(ctx context.Context, name string, xp int, cronExpr string) (int, error) {
+	if name == "" || xp <= 0 {
+		return 0, fmt.Errorf("invalid input: name is required and xp must be positive")
+	}
+	if _, err := parseCronExpr(cronExpr); err != nil {
+		return 0, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	var id int
+	err := a.withDBSlot(ctx, a.maxDBConns, func() error {
+		err := a.db.QueryRowContext(ctx,
+			"INSERT INTO recurring_tasks (name, xp, cron_expr) VALUES ($1, $2, $3) RETURNING id",
+			name, xp, cronExpr,
+		).Scan(&id)
+		if err != nil {
+			return dbErr(ctx, "error adding recurring task", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// DeleteRecurringTask removes a recurring task definition.
+func (a *AppState) DeleteRecurringTask(ctx context.Context, id int) error {
+	return a.withDBSlot(ctx, a.maxDBConns, func() error {
+		if _, err := a.db.ExecContext(ctx, "DELETE FROM recurring_tasks WHERE id = $1", id); err != nil {
+			return dbErr(ctx, "error deleting recurring task", err)
+		}
+		return nil
+	})
+}
+
+// dueRecurringTasks returns the recurring tasks whose cron schedule has
+// elapsed since they last ran. A task that has never run is due at its first
+// occurrence after CreatedAt, not immediately on the next scheduler tick.
+func (a *AppState) dueRecurringTasks(ctx context.Context, now time.Time) ([]RecurringTask, error) {
+	all, err := a.getRecurringTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []RecurringTask
+	for _, rt := range all {
+		sched, err := parseCronExpr(rt.CronExpr)
+		if err != nil {
+			continue
+		}
+
+		since := rt.CreatedAt
+		if rt.LastRunAt != nil {
+			since = *rt.LastRunAt
+		}
+		if next := sched.Next(since); next.Before(now) || next.Equal(now) {
+			due = append(due, rt)
+		}
+	}
+	return due, nil
+}
+
+// MaterializeRecurringTask inserts a new pending task row for the given recurring
+// task and records the run timestamp, all within a single transaction so a crash
+// mid-materialization can't duplicate or lose a run.
+func (a *AppState) MaterializeRecurringTask(ctx context.Context, recurringTaskID int, runAt time.Time) error {
+	return a.withDBSlot(ctx, a.maxDBConns, func() error {
+		tx, err := a.db.BeginTx(ctx, nil)
+		if err != nil {
+			return dbErr(ctx, "error starting transaction", err)
+		}
+		defer tx.Rollback()
+
+		var name string
+		var xp int
+		err = tx.QueryRowContext(ctx, "SELECT name, xp FROM recurring_tasks WHERE id = $1", recurringTaskID).Scan(&name, &xp)
+		if err != nil {
+			return dbErr(ctx, fmt.Sprintf("error loading recurring task %d", recurringTaskID), err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO tasks (name, xp, completed) VALUES ($1, $2, false)", name, xp); err != nil {
+			return dbErr(ctx, fmt.Sprintf("error materializing recurring task %d", recurringTaskID), err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE recurring_tasks SET last_run_at = $1 WHERE id = $2", runAt, recurringTaskID); err != nil {
+			return dbErr(ctx, fmt.Sprintf("error recording last run for recurring task %d", recurringTaskID), err)
+		}
+
+		return tx.Commit()
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/robfig/cron/v3"
+	"github.com/vmihailenco/taskq/v3"
+	"github.com/vmihailenco/taskq/v3/redisq"
+)
+
+const recurringTaskQueueName = "recurring-tasks"
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+func parseCronExpr(expr string) (cron.Schedule, error) {
+	return cronParser.Parse(expr)
+}
+
+// RecurringTaskQueue wraps the Redis-backed taskq queue that carries
+// materialization jobs from the scheduler goroutine to the worker pool.
+type RecurringTaskQueue struct {
+	queue taskq.Queue
+}
+
+// NewRecurringTaskQueue registers the recurring-tasks queue against the given Redis client.
+func NewRecurringTaskQueue(redisClient redis.UniversalClient) *RecurringTaskQueue {
+	factory := redisq.NewFactory()
+	queue := factory.RegisterQueue(&taskq.QueueOptions{
+		Name:  recurringTaskQueueName,
+		Redis: redisClient,
+	})
+	return &RecurringTaskQueue{queue: queue}
+}
+
+// schedulerAppState is set by StartScheduler so the registered task handler
+// below can reach AppState without threading it through taskq's args.
+var schedulerAppState *AppState
+
+var materializeRecurringTaskMsg = taskq.RegisterTask(&taskq.TaskOptions{
+	Name: "materialize-recurring-task",
+	Handler: func(ctx context.Context, recurringTaskID int, runAt time.Time) error {
+		return schedulerAppState.MaterializeRecurringTask(ctx, recurringTaskID, runAt)
+	},
+})
+
+// StartScheduler runs a goroutine that wakes up once a minute, finds recurring
+// tasks whose cron schedule is due, and enqueues a materialization job for each.
+// The actual insert happens in the worker pool so a slow or down worker can't
+// block the scheduler loop.
+func StartScheduler(ctx context.Context, state *AppState, rtq *RecurringTaskQueue) {
+	schedulerAppState = state
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				due, err := state.dueRecurringTasks(ctx, now)
+				if err != nil {
+					slog.Default().Error("scheduler: error checking due recurring tasks", "err", err)
+					continue
+				}
+				for _, rt := range due {
+					msg := materializeRecurringTaskMsg.WithArgs(ctx, rt.ID, now)
+					if err := rtq.queue.Add(msg); err != nil {
+						slog.Default().Error("scheduler: error enqueueing recurring task", "recurring_task_id", rt.ID, "err", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// StartWorkerPool starts consuming materialization jobs from the queue. Pool
+// size is controlled by the WORKER_LIMIT env var (default 4).
+func StartWorkerPool(ctx context.Context, rtq *RecurringTaskQueue) error {
+	limit := 4
+	if v := os.Getenv("WORKER_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= math.MaxInt32 {
+			limit = n
+		}
+	}
+
+	consumer := rtq.queue.Consumer()
+	consumer.Options().WorkerLimit = int32(limit)
+	return consumer.Start(ctx)
+}
+
+// handleAPIRecurringTasks serves GET /api/recurring-tasks (list) and
+// POST /api/recurring-tasks (create).
+func (a *AppState) handleAPIRecurringTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+		recurring, err := a.getRecurringTasks(ctx)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, recurring)
+	case http.MethodPost:
+		var body struct {
+			Name     string `json:"name"`
+			XP       int    `json:"xp"`
+			CronExpr string `json:"cron_expr"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		id, err := a.AddRecurringTask(ctx, body.Name, body.XP, body.CronExpr)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]int{"id": id})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleAPIRecurringTaskAction serves DELETE /api/recurring-tasks/{id}.
+func (a *AppState) handleAPIRecurringTaskAction(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/recurring-tasks/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid recurring task id")
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if err := a.DeleteRecurringTask(r.Context(), id); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "recurring task deleted"})
+}
+
+// registerRecurringTaskRoutes wires up the recurring-tasks CRUD endpoints.
+func registerRecurringTaskRoutes(state *AppState) {
+	http.HandleFunc("/api/recurring-tasks", state.handleAPIRecurringTasks)
+	http.HandleFunc("/api/recurring-tasks/", state.handleAPIRecurringTaskAction)
+}